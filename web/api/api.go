@@ -0,0 +1,91 @@
+// Package api defines the stable JSON shapes served under /api/v1/.
+// Homepage, Workers, QueuePreviews, QueueInspect and Job mirror the data
+// a corresponding HTML handler in web already builds for its template, so
+// those handlers can render either the template or the JSON encoding of
+// the same value. RequeueResult and the Bulk* types have no template
+// counterpart: they are only ever sent or received as JSON.
+package api
+
+import hb "worker/heartbeat"
+
+type Homepage struct {
+	CustomQueues []string `json:"customQueues"`
+	WorkersCount int64    `json:"workersCount"`
+	RunningCount int64    `json:"runningCount"`
+	DoneCount    int64    `json:"doneCount"`
+	FailedCount  int64    `json:"failedCount"`
+}
+
+type Worker struct {
+	WorkerName string            `json:"workerName"`
+	HbMetaData hb.WorkerMetaData `json:"hbMetaData"`
+}
+
+type Workers struct {
+	Workers []Worker `json:"workers"`
+}
+
+type QueuePreview struct {
+	Name        string `json:"name"`
+	Concurrency int    `json:"concurrency"`
+	PendingLen  int64  `json:"pendingLen"`
+	RunningLen  int64  `json:"runningLen"`
+	DelayedLen  int64  `json:"delayedLen"`
+	FailedLen   int64  `json:"failedLen"`
+	DoneLen     int64  `json:"doneLen"`
+	RetainedLen int64  `json:"retainedLen"`
+}
+
+type QueuePreviews struct {
+	AllQueues []QueuePreview `json:"allQueues"`
+}
+
+type QueueInspect struct {
+	Jobs      []string `json:"jobs"`
+	JobsID    []string `json:"jobsId"`
+	Total     int64    `json:"total"`
+	Name      string   `json:"name"`
+	PrevStart int64    `json:"prevStart"`
+	NextStart int64    `json:"nextStart"`
+}
+
+// Job is the inspectJob shape. TTLSeconds holds the remaining retention
+// TTL in whole seconds (not a time.Duration) so it serializes as a plain
+// number; joblogsfull.html/joblogs.html must render it as {{.TTLSeconds}}.
+type Job struct {
+	LogID       string `json:"logId"`
+	Logs        string `json:"logs"`
+	Result      string `json:"result,omitempty"`
+	CompletedAt string `json:"completedAt,omitempty"`
+	TTLSeconds  int64  `json:"ttlSeconds,omitempty"`
+	Retained    bool   `json:"retained"`
+}
+
+type RequeueResult struct {
+	QueueName string `json:"queueName"`
+	JobID     string `json:"jobId"`
+	Operation string `json:"operation"`
+	Status    string `json:"status"`
+}
+
+// BulkItem identifies one job within a BulkActionRequest, in the same
+// jobid/job shape the single-item requeueOrDelete query params use.
+type BulkItem struct {
+	JobID string `json:"jobid"`
+	Job   string `json:"job"`
+}
+
+// BulkActionRequest is the POST body /bulkAction accepts. Operation is
+// one of "requeue", "delete", "purge_all_failed", or "move_to_dlq".
+type BulkActionRequest struct {
+	QueueName string     `json:"queuename"`
+	Operation string     `json:"operation"`
+	Items     []BulkItem `json:"items"`
+}
+
+type BulkActionResult struct {
+	QueueName string `json:"queueName"`
+	Operation string `json:"operation"`
+	Total     int    `json:"total"`
+	Succeeded int    `json:"succeeded"`
+}
@@ -0,0 +1,132 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"worker/config"
+)
+
+// role is the privilege level a request carries once authenticated.
+// Read-only endpoints only require roleReadOnly; anything that mutates
+// queue state requires roleElevated.
+type role int
+
+const (
+	roleReadOnly role = iota
+	roleElevated
+)
+
+// elevatedPaths lists routes that mutate queue state and therefore need
+// roleElevated, whether hit through the HTMX UI or the /api/v1 sibling.
+var elevatedPaths = map[string]bool{
+	"/requeueOrDelete":        true,
+	"/api/v1/requeueOrDelete": true,
+	"/bulkAction":             true,
+}
+
+// authMiddleware gates every request behind the allowlist/basic-auth/
+// bearer-token settings read from config.Get().Admin. A request from an
+// allowlisted CIDR is treated as elevated without further checks; every
+// other request must authenticate, and the role it authenticates as must
+// meet the role the path requires.
+func authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cfg := config.Get()
+		required := roleReadOnly
+		if elevatedPaths[req.URL.Path] {
+			required = roleElevated
+		}
+
+		if remoteAllowlisted(cfg, req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		granted, ok := authenticate(cfg, req)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="badger-admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if granted < required {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func remoteAllowlisted(cfg *config.Config, req *http.Request) bool {
+	if len(cfg.Admin.AllowedCIDRs) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cfg.Admin.AllowedCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate checks the bearer token when the request carries one,
+// falling back to HTTP basic auth otherwise, and reports both whether
+// the request authenticated at all and the role it authenticated as.
+// Credentials matching the elevated settings grant roleElevated;
+// credentials matching the read-only settings grant roleReadOnly.
+func authenticate(cfg *config.Config, req *http.Request) (role, bool) {
+	if token, ok := bearerToken(req); ok {
+		switch {
+		case cfg.Admin.BearerToken != "" && secretEqual(token, cfg.Admin.BearerToken):
+			return roleElevated, true
+		case cfg.Admin.ReadOnlyBearerToken != "" && secretEqual(token, cfg.Admin.ReadOnlyBearerToken):
+			return roleReadOnly, true
+		default:
+			return roleReadOnly, false
+		}
+	}
+
+	user, pass, ok := req.BasicAuth()
+	if !ok {
+		return roleReadOnly, false
+	}
+	switch {
+	case cfg.Admin.Username != "" && user == cfg.Admin.Username && secretEqual(pass, cfg.Admin.Password):
+		return roleElevated, true
+	case cfg.Admin.ReadOnlyUsername != "" && user == cfg.Admin.ReadOnlyUsername && secretEqual(pass, cfg.Admin.ReadOnlyPassword):
+		return roleReadOnly, true
+	default:
+		return roleReadOnly, false
+	}
+}
+
+// secretEqual compares a token or password in constant time, so an
+// admin server sitting behind a reverse proxy doesn't leak credential
+// bytes through a response-timing side channel the way == would.
+func secretEqual(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// bearerToken reports the token carried by an "Authorization: Bearer
+// <token>" header, and whether the header was in that form at all - as
+// opposed to absent or using a different scheme (e.g. Basic), in which
+// case the caller should fall back to basic auth rather than treat a
+// missing bearer token as a failed bearer attempt.
+func bearerToken(req *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	h := req.Header.Get("Authorization")
+	if !strings.HasPrefix(h, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(h, prefix), true
+}
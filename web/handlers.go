@@ -3,18 +3,43 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"maps"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 
 	"worker/config"
 	db "worker/dbRedis"
 	hb "worker/heartbeat"
+	taskproto "worker/internal/proto"
+	"worker/web/api"
 )
 
+// completedKeyPrefix namespaces the per-job retention hashes written by
+// worker/resultwriter once a job finishes: badger:completed:<queue>:<jobid>.
+const completedKeyPrefix = "badger:completed:"
+
+// wantsJSON decides content negotiation for the /api/v1 siblings: either
+// the request came in through the /api/v1/ prefix, or it explicitly asked
+// for JSON via the Accept header.
+func wantsJSON(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, "/api/v1/") ||
+		strings.Contains(req.Header.Get("Accept"), "application/json")
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		errlogger(err)
+	}
+}
+
 type allworkersStruct struct {
 	listofworkers []hb.WorkerMetaData
 }
@@ -25,21 +50,7 @@ func homepage(w http.ResponseWriter, req *http.Request) {
 		redisClient = db.Get()
 		ctx         = context.Background()
 	)
-	path := filepath.Join(templateAbs, "home.html")
-	t, err := template.ParseFiles(path)
-	t = template.Must(t, nil)
-
-	if err != nil {
-		errlogger(err)
-		return
-	}
-	data := struct {
-		CustomQueues []string
-		WorkersCount int64
-		RunningCount int64
-		DoneCount    int64
-		FailedCount  int64
-	}{
+	data := api.Homepage{
 		CustomQueues: []string{},
 	}
 	workers, _ := redisClient.HLen(ctx, cfg.ClusterName).Result()
@@ -58,7 +69,21 @@ func homepage(w http.ResponseWriter, req *http.Request) {
 		data.FailedCount += failedLen
 	}
 
-	t.Execute(w, data)
+	if wantsJSON(req) {
+		writeJSON(w, data)
+		return
+	}
+
+	path := filepath.Join(templateAbs, "home.html")
+	t, err := template.ParseFiles(path)
+	t = template.Must(t, nil)
+	if err != nil {
+		errlogger(err)
+		return
+	}
+	if err := t.Execute(w, data); err != nil {
+		errlogger(err)
+	}
 }
 
 func getWorkers(w http.ResponseWriter, req *http.Request) {
@@ -66,18 +91,19 @@ func getWorkers(w http.ResponseWriter, req *http.Request) {
 		cfg         = config.Get()
 		redisClient = db.Get()
 	)
-	type singleWorkerData struct {
-		WorkerName string
-		HbMetaData hb.WorkerMetaData
-	}
 
 	ctx := context.Background()
 	allworkers, _ := redisClient.HGetAll(ctx, cfg.ClusterName).Result()
-	renderData := []singleWorkerData{}
+	data := api.Workers{Workers: []api.Worker{}}
 	for key, value := range allworkers {
-		data := singleWorkerData{WorkerName: key}
-		json.Unmarshal([]byte(value), &data.HbMetaData)
-		renderData = append(renderData, data)
+		worker := api.Worker{WorkerName: key}
+		json.Unmarshal([]byte(value), &worker.HbMetaData)
+		data.Workers = append(data.Workers, worker)
+	}
+
+	if wantsJSON(req) {
+		writeJSON(w, data)
+		return
 	}
 
 	path := filepath.Join(templateAbs, "allworkers.html")
@@ -87,13 +113,15 @@ func getWorkers(w http.ResponseWriter, req *http.Request) {
 	}
 	renderDataStruct := struct {
 		Name    string
-		Workers []singleWorkerData
+		Workers []api.Worker
 	}{
 		Name:    "Workers",
-		Workers: renderData,
+		Workers: data.Workers,
 	}
 	t = template.Must(t, nil)
-	t.Execute(w, renderDataStruct)
+	if err := t.Execute(w, renderDataStruct); err != nil {
+		errlogger(err)
+	}
 }
 
 func showQueuePreview(w http.ResponseWriter, req *http.Request) {
@@ -104,23 +132,12 @@ func showQueuePreview(w http.ResponseWriter, req *http.Request) {
 	)
 	queueNames := maps.Keys(cfg.CustomQueues.Queues)
 
-	type singleQueueStruct struct {
-		Name        string
-		Concurrency int
-		PendingLen  int64
-		RunningLen  int64
-		DelayedLen  int64
-		FailedLen   int64
-		DoneLen     int64
-	}
-	data := struct {
-		AllQueues []singleQueueStruct
-	}{
-		[]singleQueueStruct{},
+	data := api.QueuePreviews{
+		AllQueues: []api.QueuePreview{},
 	}
 
 	for queueKey := range queueNames {
-		singleQueue := singleQueueStruct{
+		singleQueue := api.QueuePreview{
 			Name:        queueKey,
 			Concurrency: cfg.CustomQueues.Queues[queueKey].Concurrency,
 		}
@@ -136,17 +153,44 @@ func showQueuePreview(w http.ResponseWriter, req *http.Request) {
 		failedLen, _ := redisClient.LLen(ctx, failedQueue).Result()
 		doneLen, _ := redisClient.LLen(ctx, doneQueue).Result()
 
+		// retainedLen stays 0 until worker/resultwriter's WriteResult is
+		// actually wired into the job-completion path (see its package
+		// doc comment) - the SCAN below is correct but has nothing to find.
+		var retainedLen int64
+		retainedPattern := completedKeyPrefix + queueKey + ":*"
+		cursor := uint64(0)
+		for {
+			keys, nextCursor, scanErr := redisClient.Scan(ctx, cursor, retainedPattern, 100).Result()
+			if scanErr != nil {
+				break
+			}
+			retainedLen += int64(len(keys))
+			cursor = nextCursor
+			if cursor == 0 {
+				break
+			}
+		}
+
 		singleQueue.PendingLen = pendingLen
 		singleQueue.RunningLen = runningLen
 		singleQueue.DelayedLen = delayedLen
 		singleQueue.FailedLen = failedLen
 		singleQueue.DoneLen = doneLen
+		singleQueue.RetainedLen = retainedLen
 		data.AllQueues = append(data.AllQueues, singleQueue)
 	}
+
+	if wantsJSON(req) {
+		writeJSON(w, data)
+		return
+	}
+
 	path := filepath.Join(templateAbs, "jobs.html")
 	tmpl, _ := template.ParseFiles(path)
 	tmpl = template.Must(tmpl, nil)
-	tmpl.Execute(w, data)
+	if err := tmpl.Execute(w, data); err != nil {
+		errlogger(err)
+	}
 }
 
 func inspectQueue(w http.ResponseWriter, req *http.Request) {
@@ -155,35 +199,6 @@ func inspectQueue(w http.ResponseWriter, req *http.Request) {
 		ctx         = context.Background()
 	)
 
-	var tmpl *template.Template
-	funcMap := template.FuncMap{
-		// The name "inc" is what the function will be called in the template text.
-		"div": func(i int64, j int64) int {
-			res := int(i / j)
-			if res <= 0 {
-				res = 1
-			}
-			return res
-		},
-	}
-
-	// check htmx headers
-	if req.Header.Get("Hx-Request") == "" {
-		path1 := filepath.Join(templateAbs, "inspectQueueFull.html")
-		path2 := filepath.Join(templateAbs, "inspectQueue.html")
-		tmpl = template.Must(
-			template.New("inspectQueueFull.html").
-				Funcs(funcMap).
-				ParseFiles(path1, path2))
-
-	} else {
-		path1 := filepath.Join(templateAbs, "inspectQueue.html")
-		tmpl = template.Must(
-			template.New("inspectQueue.html").
-				Funcs(funcMap).
-				ParseFiles(path1))
-	}
-
 	// get queries
 	queueName := req.URL.Query().Get("queuename")
 	startStr := req.URL.Query().Get("start")
@@ -194,14 +209,7 @@ func inspectQueue(w http.ResponseWriter, req *http.Request) {
 	var jobsRange int64 = 10
 	stop := start + jobsRange
 
-	data := struct {
-		Jobs      []string
-		JobsID    []string
-		Total     int64
-		Name      string
-		PrevStart int64
-		NextStart int64
-	}{Jobs: []string{}}
+	data := api.QueueInspect{Jobs: []string{}}
 	if strings.HasPrefix(queueName, "badger:running") {
 		res, _ := redisClient.HGetAll(ctx, queueName).Result()
 		ks := maps.Keys(res)
@@ -213,18 +221,25 @@ func inspectQueue(w http.ResponseWriter, req *http.Request) {
 		res, _ := redisClient.LRange(ctx, queueName, start, stop).Result()
 
 		for _, v := range res {
-
-			s := strings.Split(v, ":")
-			if len(s) > 1 {
-				id := s[0]
-				job := s[1]
-				data.Jobs = append(data.Jobs, job)
-				data.JobsID = append(data.JobsID, id)
-			} else {
+			task, migrated, err := taskproto.UnmarshalWithMigration(queueName, []byte(v))
+			if err != nil {
 				data.Jobs = append(data.Jobs, v)
 				data.JobsID = append(data.JobsID, "")
+				continue
+			}
+			data.Jobs = append(data.Jobs, string(task.Payload))
+			data.JobsID = append(data.JobsID, task.ID)
+			if migrated {
+				// Rewrite the legacy "id:job" entry with the equivalent
+				// TaskMessage encoding so the queue has fully migrated once
+				// it drains once. queueName is concurrently LPOP/RPOP'd by
+				// workers, so an LRANGE-derived index can point at a
+				// different job by the time we'd write it back - remove the
+				// exact legacy value instead and push the re-encoded one.
+				if removed, err := redisClient.LRem(ctx, queueName, 1, v).Result(); err == nil && removed > 0 {
+					redisClient.RPush(ctx, queueName, task.Marshal())
+				}
 			}
-
 		}
 		data.Total, _ = redisClient.LLen(ctx, queueName).Result()
 	}
@@ -234,7 +249,43 @@ func inspectQueue(w http.ResponseWriter, req *http.Request) {
 	if data.PrevStart < 0 {
 		data.PrevStart = 0
 	}
-	tmpl.Execute(w, data)
+
+	if wantsJSON(req) {
+		writeJSON(w, data)
+		return
+	}
+
+	funcMap := template.FuncMap{
+		// The name "div" is what the function will be called in the template text.
+		"div": func(i int64, j int64) int {
+			res := int(i / j)
+			if res <= 0 {
+				res = 1
+			}
+			return res
+		},
+	}
+
+	var tmpl *template.Template
+	// check htmx headers
+	if req.Header.Get("Hx-Request") == "" {
+		path1 := filepath.Join(templateAbs, "inspectQueueFull.html")
+		path2 := filepath.Join(templateAbs, "inspectQueue.html")
+		tmpl = template.Must(
+			template.New("inspectQueueFull.html").
+				Funcs(funcMap).
+				ParseFiles(path1, path2))
+
+	} else {
+		path1 := filepath.Join(templateAbs, "inspectQueue.html")
+		tmpl = template.Must(
+			template.New("inspectQueue.html").
+				Funcs(funcMap).
+				ParseFiles(path1))
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		errlogger(err)
+	}
 }
 
 func inspectJob(w http.ResponseWriter, req *http.Request) {
@@ -244,6 +295,41 @@ func inspectJob(w http.ResponseWriter, req *http.Request) {
 	)
 	htmxHeader := req.Header.Get("Hx-Request")
 	logid := req.URL.Query().Get("logid")
+	queueName := req.URL.Query().Get("queuename")
+	jobId := req.URL.Query().Get("jobid")
+
+	res, _ := redisClient.HGet(ctx, "badger:joblog", logid).Result()
+	data := api.Job{LogID: logid, Logs: res}
+	if data.Logs == "" {
+		data.Logs = "Logging is set to false in config file"
+	}
+
+	// Retained/Result/CompletedAt/TTLSeconds stay zero-valued until
+	// worker/resultwriter's WriteResult is wired into the job-completion
+	// path (see its package doc comment) - this HGetAll is correct but
+	// the hash it reads is never written yet.
+	if queueName != "" && jobId != "" {
+		retentionKey := completedKeyPrefix + queueName + ":" + jobId
+		retained, _ := redisClient.HGetAll(ctx, retentionKey).Result()
+		if len(retained) > 0 {
+			data.Retained = true
+			data.Result = retained["result"]
+			if completedAtUnix, err := strconv.ParseInt(retained["completedAt"], 10, 64); err == nil {
+				data.CompletedAt = time.Unix(completedAtUnix, 0).Format(time.RFC3339)
+			}
+			data.TTLSeconds = int64(redisClient.TTL(ctx, retentionKey).Val().Seconds())
+		}
+	}
+	// Retained == false only means no retention hash was found for
+	// queuename/jobid (either param missing, or the hash was never written
+	// or has expired); this handler does not check badger:running:<queue>,
+	// so it does not by itself mean the job is still running.
+
+	if wantsJSON(req) {
+		writeJSON(w, data)
+		return
+	}
+
 	var tmpl *template.Template
 	if htmxHeader == "" {
 		path1 := filepath.Join(templateAbs, "joblogsfull.html")
@@ -254,16 +340,78 @@ func inspectJob(w http.ResponseWriter, req *http.Request) {
 		path := filepath.Join(templateAbs, "joblogs.html")
 		tmpl, _ = template.ParseFiles(path)
 	}
+	if err := tmpl.Execute(w, data); err != nil {
+		errlogger(err)
+	}
+}
 
-	res, _ := redisClient.HGet(ctx, "badger:joblog", logid).Result()
-	data := struct {
-		Logs string
-	}{}
-	data.Logs = res
-	if data.Logs == "" {
-		data.Logs = "Logging is set to false in config file"
+// streamJobLogs upgrades to text/event-stream and pushes new log lines as
+// the worker appends them to badger:joblog, by subscribing to the pub/sub
+// channel the worker publishes to on each log write. It replaces the
+// one-shot HGet view inspectJob uses for jobs that are still running.
+//
+// STATUS: this is the subscriber side only. Nothing in this tree yet
+// publishes to badger:joblog:events:<logid> - the worker's job-logging
+// path (outside this tree) still needs a PUBLISH alongside its existing
+// HSet/append to badger:joblog on every log write. Until that call
+// lands, this endpoint stays open and simply never receives an event.
+func streamJobLogs(w http.ResponseWriter, req *http.Request) {
+	var (
+		redisClient = db.Get()
+		ctx         = req.Context()
+	)
+	logid := req.URL.Query().Get("logid")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	pubsub := redisClient.Subscribe(ctx, "badger:joblog:events:"+logid)
+	defer pubsub.Close()
+
+	events := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-events:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", msg.Payload)
+			flusher.Flush()
+		}
+	}
+}
+
+// rawEntriesByID LRanges the full contents of queueName once and indexes
+// each entry's exact on-the-wire bytes by job ID, decoding legacy
+// "id:job" entries the same way inspectQueue does. Callers that need to
+// LRem a specific job must match against these raw bytes rather than
+// re-encoding a fresh TaskMessage from just the jobid/job query params:
+// the stored entry may carry fields (Queue, retry count, ...) those
+// params don't have, and LRem only matches an exact byte-for-byte value.
+func rawEntriesByID(ctx context.Context, redisClient redis.Cmdable, queueName string) map[string]string {
+	entries, err := redisClient.LRange(ctx, queueName, 0, -1).Result()
+	if err != nil {
+		return nil
+	}
+	byID := make(map[string]string, len(entries))
+	for _, v := range entries {
+		task, _, err := taskproto.UnmarshalWithMigration(queueName, []byte(v))
+		if err != nil || task.ID == "" {
+			continue
+		}
+		byID[task.ID] = v
 	}
-	tmpl.Execute(w, data)
+	return byID
 }
 
 func requeueOrDelete(w http.ResponseWriter, req *http.Request) {
@@ -275,16 +423,154 @@ func requeueOrDelete(w http.ResponseWriter, req *http.Request) {
 	job := req.URL.Query().Get("job")
 	queueName := req.URL.Query().Get("queuename")
 	operation := req.URL.Query().Get("operation")
+	task := &taskproto.TaskMessage{ID: jobId, Payload: []byte(job)}
+	raw, ok := rawEntriesByID(ctx, redisClient, queueName)[jobId]
+	if !ok {
+		raw = string(task.Marshal())
+	}
 	if operation == "delete" {
-		redisClient.LRem(ctx, queueName, 1, jobId+":"+job)
+		redisClient.LRem(ctx, queueName, 1, raw)
 	} else {
 		s := strings.Split(queueName, ":")
 		if len(s) == 3 {
 			pendingQueue := s[0] + ":pending:" + s[2]
-			redisClient.LPush(ctx, pendingQueue, job)
+			// Push the entry as it was actually stored (preserving
+			// RetryCount/TimeoutSeconds/DeadlineUnix/Result) rather than a
+			// bare TaskMessage{ID, Payload}, matching bulkAction's requeue
+			// case. Only fall back to task.Queue = s[2] when raw wasn't
+			// found, since the fallback TaskMessage has no Queue set yet.
+			if !ok {
+				task.Queue = s[2]
+				raw = string(task.Marshal())
+			}
+			redisClient.LRem(ctx, queueName, 1, raw)
+			redisClient.LPush(ctx, pendingQueue, raw)
 		}
 	}
 
+	if wantsJSON(req) {
+		writeJSON(w, api.RequeueResult{
+			QueueName: queueName,
+			JobID:     jobId,
+			Operation: operation,
+			Status:    "ok",
+		})
+		return
+	}
+
 	redirectUrl := "/inspectQueue?start=0" + "&queuename=" + queueName
 	http.Redirect(w, req, redirectUrl, http.StatusMovedPermanently)
 }
+
+// bulkAction is the multi-item sibling of requeueOrDelete: it accepts a
+// POSTed api.BulkActionRequest and runs every item's Redis commands inside
+// one MULTI/EXEC pipeline, so an operator draining thousands of failed
+// jobs after an outage doesn't leave the failed list half-mutated if one
+// command in the batch fails.
+func bulkAction(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body api.BulkActionRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		redisClient = db.Get()
+		ctx         = context.Background()
+	)
+	result := api.BulkActionResult{
+		QueueName: body.QueueName,
+		Operation: body.Operation,
+		Total:     len(body.Items),
+	}
+
+	if body.Operation == "purge_all_failed" {
+		// This is a wholesale Del, not a per-item trim - refuse to run it
+		// against anything but a failed-queue key, or a typo'd queuename
+		// (pending, done, a dlq) gets wiped outright.
+		if !strings.HasPrefix(body.QueueName, "badger:failed:") {
+			http.Error(w, "purge_all_failed requires a badger:failed:<queue> queuename", http.StatusBadRequest)
+			return
+		}
+		if err := redisClient.Del(ctx, body.QueueName).Err(); err != nil {
+			errlogger(err)
+		} else {
+			result.Succeeded = result.Total
+		}
+		writeJSON(w, result)
+		return
+	}
+
+	var pendingQueue, dlqQueue string
+	if s := strings.Split(body.QueueName, ":"); len(s) == 3 {
+		pendingQueue = s[0] + ":pending:" + s[2]
+		dlqQueue = s[0] + ":dlq:" + s[2]
+	}
+
+	// Look up the exact stored bytes for each job ID up front: LRem only
+	// matches a byte-for-byte value, and re-encoding a fresh TaskMessage
+	// from just item.JobID/item.Job (with no Queue field) never matches
+	// an entry that was migrated or produced with Queue set.
+	raw := rawEntriesByID(ctx, redisClient, body.QueueName)
+
+	removeCmds := make([]*redis.IntCmd, len(body.Items))
+	pushCmds := make([]*redis.IntCmd, len(body.Items))
+	_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, item := range body.Items {
+			// requeue/move_to_dlq need a target queue to push into. When
+			// body.QueueName doesn't split into the expected 3 segments,
+			// skip the item entirely rather than letting the LRem below
+			// fire anyway - that would delete the job from its source
+			// queue with nowhere for it to land, exactly the silent
+			// job-loss the purge_all_failed namespace check above guards
+			// against for its own operation.
+			if body.Operation == "requeue" && pendingQueue == "" {
+				continue
+			}
+			if body.Operation == "move_to_dlq" && dlqQueue == "" {
+				continue
+			}
+
+			encoded, ok := raw[item.JobID]
+			if !ok {
+				task := &taskproto.TaskMessage{ID: item.JobID, Payload: []byte(item.Job)}
+				encoded = string(task.Marshal())
+			}
+			switch body.Operation {
+			case "delete":
+				removeCmds[i] = pipe.LRem(ctx, body.QueueName, 1, encoded)
+			case "requeue":
+				removeCmds[i] = pipe.LRem(ctx, body.QueueName, 1, encoded)
+				pushCmds[i] = pipe.LPush(ctx, pendingQueue, encoded)
+			case "move_to_dlq":
+				removeCmds[i] = pipe.LRem(ctx, body.QueueName, 1, encoded)
+				pushCmds[i] = pipe.LPush(ctx, dlqQueue, encoded)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		errlogger(err)
+	}
+	// TxPipelined only reports whether the pipeline itself errored, not
+	// whether any individual LRem actually removed something - count the
+	// per-item command results so a batch of no-op/skipped items is
+	// reported accurately instead of claiming the whole batch worked.
+	for i := range body.Items {
+		removed := removeCmds[i] != nil && removeCmds[i].Val() > 0
+		if !removed {
+			continue
+		}
+		if pushCmds[i] != nil && pushCmds[i].Val() == 0 {
+			continue
+		}
+		result.Succeeded++
+	}
+
+	writeJSON(w, result)
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"context"
+	"time"
+
+	"worker/config"
+	db "worker/dbRedis"
+)
+
+// doneSweepInterval is how often StartDoneSweeper trims badger:done:<queue>
+// lists down to their configured cap.
+const doneSweepInterval = 5 * time.Minute
+
+// StartDoneSweeper runs in the background for as long as ctx is alive,
+// trimming every queue's badger:done:<queue> list back down to its
+// configured DoneCap so it doesn't grow unbounded between operator
+// visits. Queues with no cap configured are left alone.
+func StartDoneSweeper(ctx context.Context) {
+	ticker := time.NewTicker(doneSweepInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sweepDoneQueues(ctx)
+			}
+		}
+	}()
+}
+
+func sweepDoneQueues(ctx context.Context) {
+	var (
+		cfg         = config.Get()
+		redisClient = db.Get()
+	)
+	for key, queueCfg := range cfg.CustomQueues.Queues {
+		if queueCfg.DoneCap <= 0 {
+			continue
+		}
+		doneQueue := "badger:done:" + key
+		if err := redisClient.LTrim(ctx, doneQueue, 0, int64(queueCfg.DoneCap)-1).Err(); err != nil {
+			errlogger(err)
+		}
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
@@ -35,13 +38,31 @@ func WebStart() {
 	mux.HandleFunc("/showQueuePreview", showQueuePreview)
 	mux.HandleFunc("/inspectQueue", inspectQueue)
 	mux.HandleFunc("/inspectJob", inspectJob)
+	mux.HandleFunc("/streamJobLogs", streamJobLogs)
 	mux.HandleFunc("/requeueOrDelete", requeueOrDelete)
+	mux.HandleFunc("/bulkAction", bulkAction)
+
+	// JSON siblings of the HTML handlers above, for external dashboards,
+	// CLI tooling, and anything that'd rather not scrape the HTMX pages.
+	// Content negotiation in the handlers themselves also honours
+	// "Accept: application/json" on the plain routes.
+	mux.HandleFunc("/api/v1/", homepage)
+	mux.HandleFunc("/api/v1/allworkers", getWorkers)
+	mux.HandleFunc("/api/v1/showQueuePreview", showQueuePreview)
+	mux.HandleFunc("/api/v1/inspectQueue", inspectQueue)
+	mux.HandleFunc("/api/v1/inspectJob", inspectJob)
+	mux.HandleFunc("/api/v1/requeueOrDelete", requeueOrDelete)
+
+	metricsRegistry := prometheus.NewRegistry()
+	metricsRegistry.MustRegister(queueCollector{})
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
 
 	ctx, cancelCtx := context.WithCancel(context.Background())
 	defer cancelCtx()
+	StartDoneSweeper(ctx)
 	server := &http.Server{
 		Addr:    "127.0.0.1:5000",
-		Handler: mux,
+		Handler: authMiddleware(mux),
 		BaseContext: func(l net.Listener) context.Context {
 			ctx := context.WithValue(ctx, keyServeraddr, l.Addr().String())
 			return ctx
@@ -0,0 +1,176 @@
+// Package proto holds the wire format queued jobs are marshaled into.
+// TaskMessage is hand-encoded against protowire rather than generated by
+// protoc, since the message is small and stable enough that the extra
+// codegen step and its registry bookkeeping aren't worth the weight.
+package proto
+
+import (
+	"errors"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// wireVersion is a single leading byte that marks a value as
+// TaskMessage-encoded. Protobuf's wire format has no marker of its own,
+// and an arbitrary legacy "id:job" string can coincidentally parse as a
+// well-formed (if semantically garbage) protobuf message, so
+// UnmarshalWithMigration needs something structural to key off rather
+// than sniffing whether decoding happened to succeed.
+const wireVersion byte = 0xBD
+
+// errNotTaskMessage is returned by Unmarshal when data doesn't start
+// with wireVersion, so UnmarshalWithMigration can fall back to the
+// legacy decoder instead of receiving a nil *TaskMessage.
+var errNotTaskMessage = errors.New("proto: data is not TaskMessage-encoded")
+
+// TaskMessage is a single queued job: the legacy string format's id and
+// job payload, plus the typed fields the UI can now surface directly
+// instead of re-parsing a colon-joined string.
+type TaskMessage struct {
+	ID               string
+	Queue            string
+	Payload          []byte
+	TimeoutSeconds   int64
+	DeadlineUnix     int64
+	RetryCount       int32
+	RetentionSeconds int64
+	Result           []byte
+}
+
+const (
+	fieldID = iota + 1
+	fieldQueue
+	fieldPayload
+	fieldTimeoutSeconds
+	fieldDeadlineUnix
+	fieldRetryCount
+	fieldRetentionSeconds
+	fieldResult
+)
+
+// Marshal encodes m using the protobuf wire format described in
+// task.proto, prefixed with wireVersion so Unmarshal can tell a
+// TaskMessage-encoded value apart from the legacy "id:job" format.
+func (m *TaskMessage) Marshal() []byte {
+	b := []byte{wireVersion}
+	if m.ID != "" {
+		b = protowire.AppendTag(b, fieldID, protowire.BytesType)
+		b = protowire.AppendString(b, m.ID)
+	}
+	if m.Queue != "" {
+		b = protowire.AppendTag(b, fieldQueue, protowire.BytesType)
+		b = protowire.AppendString(b, m.Queue)
+	}
+	if len(m.Payload) > 0 {
+		b = protowire.AppendTag(b, fieldPayload, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Payload)
+	}
+	if m.TimeoutSeconds != 0 {
+		b = protowire.AppendTag(b, fieldTimeoutSeconds, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.TimeoutSeconds))
+	}
+	if m.DeadlineUnix != 0 {
+		b = protowire.AppendTag(b, fieldDeadlineUnix, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.DeadlineUnix))
+	}
+	if m.RetryCount != 0 {
+		b = protowire.AppendTag(b, fieldRetryCount, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.RetryCount))
+	}
+	if m.RetentionSeconds != 0 {
+		b = protowire.AppendTag(b, fieldRetentionSeconds, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.RetentionSeconds))
+	}
+	if len(m.Result) > 0 {
+		b = protowire.AppendTag(b, fieldResult, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Result)
+	}
+	return b
+}
+
+// Unmarshal decodes a TaskMessage previously produced by Marshal. Unknown
+// fields are skipped so the format can grow without breaking old readers.
+// It returns errNotTaskMessage if data doesn't carry the wireVersion
+// prefix Marshal writes.
+func Unmarshal(data []byte) (*TaskMessage, error) {
+	if len(data) == 0 || data[0] != wireVersion {
+		return nil, errNotTaskMessage
+	}
+	data = data[1:]
+
+	m := &TaskMessage{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			switch num {
+			case fieldID:
+				m.ID = string(v)
+			case fieldQueue:
+				m.Queue = string(v)
+			case fieldPayload:
+				m.Payload = append([]byte(nil), v...)
+			case fieldResult:
+				m.Result = append([]byte(nil), v...)
+			}
+			data = data[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			switch num {
+			case fieldTimeoutSeconds:
+				m.TimeoutSeconds = int64(v)
+			case fieldDeadlineUnix:
+				m.DeadlineUnix = int64(v)
+			case fieldRetryCount:
+				m.RetryCount = int32(v)
+			case fieldRetentionSeconds:
+				m.RetentionSeconds = int64(v)
+			}
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return m, nil
+}
+
+// UnmarshalWithMigration decodes a queue entry that may still be in the
+// legacy "id:job" colon-joined format predating TaskMessage. It returns
+// the decoded message and whether the entry was legacy (and therefore
+// should be rewritten in place by the caller so the queue drains clean).
+// Dispatch is on the wireVersion prefix rather than "did Unmarshal not
+// error," since a legacy string has no guarantee of failing to parse as
+// protobuf.
+func UnmarshalWithMigration(queue string, data []byte) (msg *TaskMessage, migrated bool, err error) {
+	if len(data) > 0 && data[0] == wireVersion {
+		msg, err := Unmarshal(data)
+		if err != nil {
+			return nil, false, err
+		}
+		return msg, false, nil
+	}
+
+	s := string(data)
+	id, job, found := strings.Cut(s, ":")
+	if !found {
+		return &TaskMessage{Queue: queue, Payload: data}, true, nil
+	}
+	return &TaskMessage{ID: id, Queue: queue, Payload: []byte(job)}, true, nil
+}
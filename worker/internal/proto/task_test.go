@@ -0,0 +1,78 @@
+package proto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	want := &TaskMessage{
+		ID:               "job-1",
+		Queue:            "badger:failed:foo",
+		Payload:          []byte("some:payload:with:colons"),
+		TimeoutSeconds:   30,
+		DeadlineUnix:     1700000000,
+		RetryCount:       3,
+		RetentionSeconds: 86400,
+		Result:           []byte("ok"),
+	}
+
+	got, err := Unmarshal(want.Marshal())
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.ID != want.ID || got.Queue != want.Queue || !bytes.Equal(got.Payload, want.Payload) ||
+		got.TimeoutSeconds != want.TimeoutSeconds || got.DeadlineUnix != want.DeadlineUnix ||
+		got.RetryCount != want.RetryCount || got.RetentionSeconds != want.RetentionSeconds ||
+		!bytes.Equal(got.Result, want.Result) {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalRejectsUnversionedData(t *testing.T) {
+	if _, err := Unmarshal([]byte("42:do-the-thing")); err == nil {
+		t.Fatal("expected Unmarshal to reject data without the wireVersion prefix")
+	}
+	if _, err := Unmarshal(nil); err == nil {
+		t.Fatal("expected Unmarshal to reject empty data")
+	}
+}
+
+func TestUnmarshalWithMigrationLegacyString(t *testing.T) {
+	msg, migrated, err := UnmarshalWithMigration("badger:failed:foo", []byte("42:do-the-thing"))
+	if err != nil {
+		t.Fatalf("UnmarshalWithMigration: %v", err)
+	}
+	if !migrated {
+		t.Fatal("expected legacy entry to be reported as migrated")
+	}
+	if msg.ID != "42" || string(msg.Payload) != "do-the-thing" || msg.Queue != "badger:failed:foo" {
+		t.Fatalf("unexpected migration result: %+v", msg)
+	}
+}
+
+func TestUnmarshalWithMigrationLegacyStringWithColonInPayload(t *testing.T) {
+	// This is the exact case the colon-split format breaks on: a payload
+	// that itself contains a colon.
+	msg, migrated, err := UnmarshalWithMigration("badger:failed:foo", []byte("42:http://example.com"))
+	if err != nil {
+		t.Fatalf("UnmarshalWithMigration: %v", err)
+	}
+	if !migrated || msg.ID != "42" || string(msg.Payload) != "http://example.com" {
+		t.Fatalf("unexpected migration result: %+v", msg)
+	}
+}
+
+func TestUnmarshalWithMigrationAlreadyEncoded(t *testing.T) {
+	original := &TaskMessage{ID: "42", Queue: "badger:failed:foo", Payload: []byte("do-the-thing")}
+	msg, migrated, err := UnmarshalWithMigration("badger:failed:foo", original.Marshal())
+	if err != nil {
+		t.Fatalf("UnmarshalWithMigration: %v", err)
+	}
+	if migrated {
+		t.Fatal("expected an already-encoded TaskMessage not to be reported as migrated")
+	}
+	if msg.ID != "42" || string(msg.Payload) != "do-the-thing" {
+		t.Fatalf("unexpected decode result: %+v", msg)
+	}
+}
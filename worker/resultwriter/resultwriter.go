@@ -0,0 +1,77 @@
+// Package resultwriter persists the retention record (payload, completion
+// time, worker ID, result) a finished job is read back from by
+// web.inspectJob and web.showQueuePreview.
+//
+// STATUS: scaffolding only. Nothing in this tree calls WriteResult or
+// PersistCompletion yet - see the doc comment on ResultWriter for the
+// call site that still needs adding in worker/heartbeat, which is not
+// part of this tree. Until that call lands, badger:completed:<queue>:
+// <jobid> is never written: showQueuePreview's "Completed (retained)"
+// count stays 0 and inspectJob's Result/CompletedAt/TTLSeconds never
+// populate, regardless of how many jobs actually complete.
+package resultwriter
+
+import (
+	"context"
+	"time"
+
+	"worker/config"
+	db "worker/dbRedis"
+)
+
+// completedKeyPrefix is the hash key namespace retained completions live
+// under, one hash per job: badger:completed:<queue>:<jobid>.
+const completedKeyPrefix = "badger:completed:"
+
+// ResultWriter lets the job execution side persist whatever a job
+// produced into its retention record. Workers obtain one via New and
+// call WriteResult once the job finishes (success or failure), so the
+// retention hash carries the result, payload, completion time and
+// worker ID by the time web/inspectJob reads it back.
+//
+// The call site is the heartbeat/worker execution loop's job-completion
+// branch (worker/heartbeat, not part of this package) — wherever a job
+// currently transitions out of badger:running:<queue> and onto
+// badger:done:<queue> is where WriteResult belongs, in place of (or
+// alongside) that done-queue push.
+type ResultWriter interface {
+	WriteResult(ctx context.Context, queue, jobID, payload, workerID string, result []byte, completedAt time.Time) error
+}
+
+// redisResultWriter stores the full retention record - payload,
+// completion timestamp, worker ID and result - on one hash per job, and
+// applies the queue's configured retention TTL.
+type redisResultWriter struct{}
+
+// New returns the default ResultWriter, backed by the Redis client the
+// rest of the worker package already uses.
+func New() ResultWriter {
+	return redisResultWriter{}
+}
+
+func (redisResultWriter) WriteResult(ctx context.Context, queue, jobID, payload, workerID string, result []byte, completedAt time.Time) error {
+	return PersistCompletion(ctx, queue, jobID, payload, workerID, result, completedAt)
+}
+
+// PersistCompletion writes the full retention record for a finished job
+// in one HSet and sets the per-queue TTL configured for that queue,
+// defaulting to no expiry when the queue has none configured.
+func PersistCompletion(ctx context.Context, queue, jobID, payload, workerID string, result []byte, completedAt time.Time) error {
+	redisClient := db.Get()
+	key := completedKeyPrefix + queue + ":" + jobID
+	err := redisClient.HSet(ctx, key,
+		"payload", payload,
+		"completedAt", completedAt.Unix(),
+		"workerId", workerID,
+		"result", result,
+	).Err()
+	if err != nil {
+		return err
+	}
+
+	cfg := config.Get()
+	if queueCfg, ok := cfg.CustomQueues.Queues[queue]; ok && queueCfg.RetentionTTL > 0 {
+		return redisClient.Expire(ctx, key, queueCfg.RetentionTTL).Err()
+	}
+	return nil
+}